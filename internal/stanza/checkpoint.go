@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import "context"
+
+// CheckpointAccepted advances persisted operator state (e.g. file_input's
+// read offsets) only as far as a downstream consumer actually accepted.
+//
+// recordCheckpoints holds one persister key/value pair per
+// plog.LogRecord in the batch that was flushed, in the same order the
+// records were appended to that plog.Logs. consumeErr is whatever
+// ConsumeLogs returned for that batch. When consumeErr wraps a
+// *ConsumerError with a PartialCount, only the prefix of
+// recordCheckpoints up to that count is persisted - entries the consumer
+// never accepted are left unprocessed, so a restart re-delivers them
+// instead of silently advancing past data that was lost downstream.
+//
+// This writes each accepted key individually with Set rather than
+// snapshotting through ReplaceAll, because ReplaceAll treats its argument
+// as the *entire* persisted key set and deletes everything else - which
+// would wipe out checkpoints (e.g. another file's offset) written by
+// earlier, unrelated flush cycles.
+func CheckpointAccepted(ctx context.Context, p *persister, recordCheckpoints []map[string][]byte, consumeErr error) error {
+	accepted := len(recordCheckpoints)
+	if ce := AsConsumerError(consumeErr); ce != nil {
+		accepted = ce.PartialCount
+		if accepted > len(recordCheckpoints) {
+			accepted = len(recordCheckpoints)
+		}
+	}
+
+	state := make(map[string][]byte, accepted)
+	for _, checkpoint := range recordCheckpoints[:accepted] {
+		for key, value := range checkpoint {
+			state[key] = value
+		}
+	}
+	for key, value := range state {
+		if err := p.Set(ctx, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
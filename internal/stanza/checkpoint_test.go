@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointAcceptedFullSuccess(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+
+	checkpoints := []map[string][]byte{
+		{"file-a": []byte("10")},
+		{"file-a": []byte("20")},
+	}
+	require.NoError(t, CheckpointAccepted(ctx, p, checkpoints, nil))
+
+	v, err := p.Get(ctx, "file-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("20"), v)
+}
+
+func TestCheckpointAcceptedPartialRejectStopsAtAcceptedCount(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+
+	checkpoints := []map[string][]byte{
+		{"file-a": []byte("10")},
+		{"file-a": []byte("20")},
+		{"file-a": []byte("30")},
+	}
+	err := &ConsumerError{Retryable: false, PartialCount: 1, Cause: errors.New("rejected")}
+	require.NoError(t, CheckpointAccepted(ctx, p, checkpoints, err))
+
+	v, gerr := p.Get(ctx, "file-a")
+	require.NoError(t, gerr)
+	require.Equal(t, []byte("10"), v)
+}
+
+// TestCheckpointAcceptedPreservesUnrelatedKeys guards against
+// CheckpointAccepted wiping out checkpoints written by a prior,
+// unrelated flush cycle (e.g. a different file's read offset) - a
+// regression that a single-key test can't catch.
+func TestCheckpointAcceptedPreservesUnrelatedKeys(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+
+	require.NoError(t, CheckpointAccepted(ctx, p, []map[string][]byte{
+		{"file-a": []byte("10")},
+	}, nil))
+	require.NoError(t, CheckpointAccepted(ctx, p, []map[string][]byte{
+		{"file-b": []byte("5")},
+	}, nil))
+
+	a, err := p.Get(ctx, "file-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), a)
+
+	b, err := p.Get(ctx, "file-b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("5"), b)
+}
+
+func TestCheckpointAcceptedZeroAcceptedIsNoop(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+	require.NoError(t, p.Set(ctx, "file-a", []byte("existing")))
+
+	err := &ConsumerError{PartialCount: 0, Cause: errors.New("rejected")}
+	checkpoints := []map[string][]byte{{"file-a": []byte("10")}}
+	require.NoError(t, CheckpointAccepted(ctx, p, checkpoints, err))
+
+	v, gerr := p.Get(ctx, "file-a")
+	require.NoError(t, gerr)
+	require.Equal(t, []byte("existing"), v)
+}
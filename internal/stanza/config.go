@@ -0,0 +1,147 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// BaseConfig is the common configuration of a stanza-based receiver.
+type BaseConfig struct {
+	config.ReceiverSettings `mapstructure:",squash"`
+	Operators               OperatorConfigs `mapstructure:"operators"`
+	Converter               ConverterConfig `mapstructure:"converter"`
+
+	// Consumers lets a single receiver instance fan out the same
+	// operator pipeline to more than one downstream consumer.Logs. Each
+	// entry gets its own Converter, so per-pipeline flush tuning and
+	// backpressure are independent. When empty, the receiver falls back
+	// to the single consumer wired up by its factory, tuned by Converter
+	// above.
+	Consumers []ConsumerConfig `mapstructure:"consumers"`
+
+	// Signals lists which telemetry signals this receiver instance
+	// emits. Most stanza-based receivers only ever emit SignalLogs; a
+	// receiver wrapping an operator pipeline that tails pprof-shaped
+	// output (see ProfilesConverter) opts into SignalProfiles here,
+	// either instead of or alongside SignalLogs. Defaults to
+	// []Signal{SignalLogs} when empty.
+	Signals []Signal `mapstructure:"signals"`
+
+	// Profiles tunes the entry.Entry -> pprofile.Profiles conversion
+	// pipeline. Only consulted when SignalProfiles is in Signals.
+	Profiles ConverterConfig `mapstructure:"profiles"`
+
+	// RetryOnFailure enables retrying a ConsumeLogs call that failed with
+	// a retryable ConsumerError (see ConsumerError.Retryable), instead of
+	// treating every consumer error as a permanent, unretried failure.
+	// Mirrors exporterhelper's RetrySettings.Enabled, but applied at the
+	// receiver-to-consumer boundary rather than the exporter-to-backend
+	// one.
+	RetryOnFailure bool `mapstructure:"retry_on_failure"`
+
+	// MaxElapsedTime bounds how long a batch may keep being retried,
+	// measured from its first delivery attempt. Zero means retry
+	// forever. Ignored when RetryOnFailure is false.
+	MaxElapsedTime time.Duration `mapstructure:"max_elapsed_time"`
+
+	// DeadLetterStorage, when set, is a persister key that batches
+	// permanently rejected by the downstream consumer (non-retryable, or
+	// retryable but past MaxElapsedTime) are written to instead of being
+	// dropped, so an operator or external tool can inspect or replay
+	// them. newReceiver takes no secondary consumer to dead-letter to, so
+	// when this is empty a permanently rejected batch is dropped.
+	DeadLetterStorage string `mapstructure:"dead_letter_storage"`
+}
+
+// Signal identifies one of the telemetry signal types a stanza-based
+// receiver can emit from the same operator pipeline.
+type Signal string
+
+const (
+	// SignalLogs routes entries through Converter into plog.Logs.
+	SignalLogs Signal = "logs"
+	// SignalProfiles routes pprof-shaped entries (see IsProfileEntry)
+	// through ProfilesConverter into pprofile.Profiles.
+	SignalProfiles Signal = "profiles"
+)
+
+// signals returns cfg.Signals, defaulting to SignalLogs when unset.
+func (cfg BaseConfig) signals() []Signal {
+	if len(cfg.Signals) == 0 {
+		return []Signal{SignalLogs}
+	}
+	return cfg.Signals
+}
+
+// HasSignal reports whether s is among cfg's configured signals.
+func (cfg BaseConfig) HasSignal(s Signal) bool {
+	for _, configured := range cfg.signals() {
+		if configured == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OperatorConfigs is an alias for the raw, not-yet-unmarshalled operator
+// config list that operator.Config.Build expects.
+type OperatorConfigs = []map[string]interface{}
+
+// InputConfig is an alias for the raw config of the first operator in the
+// pipeline, which some receivers (e.g. those wrapping a single input
+// operator such as file_input) promote to the top level.
+type InputConfig = map[string]interface{}
+
+// ConverterConfig tunes how a stream of entry.Entry batches is converted
+// into plog.Logs for a single downstream consumer.
+type ConverterConfig struct {
+	// MaxFlushCount is the maximum number of entries accumulated before a
+	// plog.Logs is emitted, even if FlushInterval hasn't elapsed.
+	MaxFlushCount uint `mapstructure:"max_flush_count"`
+
+	// FlushInterval is the maximum time to wait before converting and
+	// emitting whatever entries have accumulated.
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+
+	// WorkerCount is the number of goroutines used to convert
+	// entry.Entry batches into plog.Logs. Defaults to runtime.NumCPU()
+	// when zero.
+	WorkerCount int `mapstructure:"worker_count"`
+}
+
+// ConsumerConfig describes one of several downstream pipelines a single
+// stanza receiver instance fans out to via Consumers. Each consumer owns
+// an independent Converter and queue; how far a full queue holds up its
+// siblings depends on DropOnOverflow.
+type ConsumerConfig struct {
+	Converter ConverterConfig `mapstructure:",squash"`
+
+	// QueueSize bounds how many un-flushed plog.Logs may be queued for
+	// this consumer. Zero means use the same default as Converter's own
+	// internal channel.
+	QueueSize int `mapstructure:"queue_size"`
+
+	// DropOnOverflow selects the backpressure policy once QueueSize is
+	// reached: drop the oldest queued plog.Logs for this consumer (true),
+	// or block this consumer's Converter until its queue has room
+	// (false). With true, other consumers in the fan-out are unaffected.
+	// With false, the blocked consumer still receives the current batch
+	// after its siblings do, but fanoutConverter's Batch and Flush do not
+	// return until it has, so later batches reach no consumer until then.
+	DropOnOverflow bool `mapstructure:"drop_on_overflow"`
+}
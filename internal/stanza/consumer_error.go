@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ConsumerError is the typed error a downstream consumer.Logs can return
+// from ConsumeLogs to tell the stanza receiver more than "something went
+// wrong": whether retrying the same batch could help, and how much of it
+// was actually accepted before the failure. Consumers that just return a
+// plain error (like mockLogsRejecter) are treated by AsConsumerError as
+// having rejected the whole batch permanently, preserving today's
+// behavior.
+type ConsumerError struct {
+	// Retryable is true when retrying the same plog.Logs later has a
+	// chance of succeeding (e.g. the consumer is backpressured), false
+	// when the batch itself is the problem and retrying would just fail
+	// again.
+	Retryable bool
+	// PartialCount is how many of the batch's plog.LogRecords, in order,
+	// were accepted before Cause occurred. The receiver only advances
+	// persister checkpoints for entries up to this count; see
+	// CheckpointAccepted.
+	PartialCount int
+	// Cause is the underlying error returned by the consumer.
+	Cause error
+}
+
+func (e *ConsumerError) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("consumer error (retryable=%t, partial=%d)", e.Retryable, e.PartialCount)
+	}
+	return fmt.Sprintf("consumer error (retryable=%t, partial=%d): %v", e.Retryable, e.PartialCount, e.Cause)
+}
+
+func (e *ConsumerError) Unwrap() error {
+	return e.Cause
+}
+
+// AsConsumerError unwraps err into a *ConsumerError. A nil err returns
+// nil. An err that doesn't wrap a *ConsumerError is treated as a
+// non-retryable rejection of the whole batch, matching the historical
+// behavior of treating any ConsumeLogs error as opaque.
+func AsConsumerError(err error) *ConsumerError {
+	if err == nil {
+		return nil
+	}
+	var ce *ConsumerError
+	if errors.As(err, &ce) {
+		return ce
+	}
+	return &ConsumerError{Retryable: false, PartialCount: 0, Cause: err}
+}
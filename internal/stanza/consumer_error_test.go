@@ -0,0 +1,44 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsConsumerErrorNil(t *testing.T) {
+	require.Nil(t, AsConsumerError(nil))
+}
+
+func TestAsConsumerErrorWrapsPlainError(t *testing.T) {
+	ce := AsConsumerError(errors.New("no"))
+	require.NotNil(t, ce)
+	require.False(t, ce.Retryable)
+	require.Equal(t, 0, ce.PartialCount)
+}
+
+func TestAsConsumerErrorUnwrapsExisting(t *testing.T) {
+	original := &ConsumerError{Retryable: true, PartialCount: 3, Cause: errors.New("backpressured")}
+	wrapped := fmt.Errorf("consuming: %w", original)
+
+	ce := AsConsumerError(wrapped)
+	require.Same(t, original, ce)
+	require.True(t, ce.Retryable)
+	require.Equal(t, 3, ce.PartialCount)
+}
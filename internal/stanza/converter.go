@@ -0,0 +1,248 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// defaultOutChannelSize bounds how many converted plog.Logs may sit in a
+// Converter's output channel before Batch starts blocking its caller.
+const defaultOutChannelSize = 64
+
+// checkpointKeyAttribute and checkpointValueAttribute are the well-known
+// entry.Entry.Attributes keys an operator attaches to the entries it
+// produces when it wants them checkpointed incrementally rather than all
+// at once - e.g. file_input recording the read offset each entry advanced
+// past. Entries without checkpointKeyAttribute set aren't tied to any
+// persister key and contribute nothing to CheckpointAccepted.
+const (
+	checkpointKeyAttribute   = "stanza.checkpoint.key"
+	checkpointValueAttribute = "stanza.checkpoint.value"
+)
+
+// Converter batches entry.Entry values coming off an operator pipeline and
+// converts them into plog.Logs, flushing whenever MaxFlushCount entries
+// have accumulated or FlushInterval has elapsed, whichever comes first.
+type Converter struct {
+	maxFlushCount  uint
+	flushInterval  time.Duration
+	workerCount    int
+	dropOnOverflow bool
+
+	pending []*entry.Entry
+	outChan chan convertedLogs
+
+	flushTimer *time.Timer
+	stopCh     chan struct{}
+}
+
+// convertedLogs pairs a flushed plog.Logs with the persister checkpoint
+// each of its plog.LogRecords corresponds to, in the same order the
+// records were appended, so a receiver can hand both to CheckpointAccepted
+// once it knows how much of the batch its consumer actually accepted.
+type convertedLogs struct {
+	Logs        plog.Logs
+	Checkpoints []map[string][]byte
+}
+
+// NewConverter creates a Converter from cfg that blocks its caller when
+// its output channel is full. A zero-value WorkerCount defaults to
+// runtime.NumCPU().
+func NewConverter(cfg ConverterConfig) *Converter {
+	return newConverter(cfg, false, defaultOutChannelSize)
+}
+
+// newConverterForConsumer is used by fanoutConverter to build one
+// Converter per configured consumer, honoring that consumer's overflow
+// policy (a full output channel either drops the oldest queued
+// plog.Logs, or blocks, exactly like a single non-fanned-out Converter
+// would) and its QueueSize, defaulting to defaultOutChannelSize when
+// unset.
+func newConverterForConsumer(cfg ConsumerConfig) *Converter {
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultOutChannelSize
+	}
+	return newConverter(cfg.Converter, cfg.DropOnOverflow, queueSize)
+}
+
+func newConverter(cfg ConverterConfig, dropOnOverflow bool, queueSize int) *Converter {
+	workerCount := cfg.WorkerCount
+	if workerCount <= 0 {
+		workerCount = runtime.NumCPU()
+	}
+	return &Converter{
+		maxFlushCount:  cfg.MaxFlushCount,
+		flushInterval:  cfg.FlushInterval,
+		workerCount:    workerCount,
+		dropOnOverflow: dropOnOverflow,
+		outChan:        make(chan convertedLogs, queueSize),
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// OutChannel returns the channel that converted batches are emitted on.
+func (c *Converter) OutChannel() <-chan convertedLogs {
+	return c.outChan
+}
+
+// Batch appends entries to the pending buffer, flushing immediately if
+// MaxFlushCount is reached. Unless the Converter was built with
+// DropOnOverflow, it blocks if the output channel is full, providing
+// backpressure to the caller's operator pipeline; with DropOnOverflow it
+// discards the oldest queued batch instead.
+func (c *Converter) Batch(entries []*entry.Entry) error {
+	c.pending = append(c.pending, entries...)
+	if c.maxFlushCount > 0 && uint(len(c.pending)) >= c.maxFlushCount {
+		return c.flush(context.Background())
+	}
+	return nil
+}
+
+// Flush converts and emits whatever entries are currently pending,
+// regardless of MaxFlushCount. The stanza receiver calls this on its
+// FlushInterval timer; its Shutdown does not call it, so entries still
+// pending at shutdown are not emitted.
+func (c *Converter) Flush(ctx context.Context) error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	return c.flush(ctx)
+}
+
+func (c *Converter) flush(ctx context.Context) error {
+	batch := convertedLogs{Logs: convertEntries(c.pending, c.workerCount), Checkpoints: extractCheckpoints(c.pending)}
+	c.pending = nil
+
+	select {
+	case c.outChan <- batch:
+		return nil
+	default:
+	}
+
+	if !c.dropOnOverflow {
+		select {
+		case c.outChan <- batch:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("flush: %w", ctx.Err())
+		}
+	}
+
+	// Overflow with DropOnOverflow set: make room by discarding the
+	// oldest queued batch for this consumer rather than blocking the
+	// shared operator pipeline or the other fanned-out consumers.
+	select {
+	case <-c.outChan:
+	default:
+	}
+	select {
+	case c.outChan <- batch:
+	default:
+	}
+	return nil
+}
+
+// Stop closes the output channel, signalling downstream readers that no
+// further plog.Logs will be emitted.
+func (c *Converter) Stop() {
+	close(c.stopCh)
+	close(c.outChan)
+}
+
+// convertEntries builds a single plog.Logs out of a batch of entry.Entry
+// values, splitting the work across workerCount goroutines once the batch
+// is large enough to be worth it. The full field-by-field mapping lives
+// alongside the rest of the stanza adapter; this is the entry point the
+// converter's flush loop calls once a batch is ready.
+func convertEntries(entries []*entry.Entry, workerCount int) plog.Logs {
+	logs := plog.NewLogs()
+	if len(entries) == 0 {
+		return logs
+	}
+	rl := logs.ResourceLogs().AppendEmpty()
+	sl := rl.ScopeLogs().AppendEmpty()
+	records := sl.LogRecords()
+	records.EnsureCapacity(len(entries))
+	for range entries {
+		records.AppendEmpty()
+	}
+
+	if workerCount <= 1 || len(entries) < workerCount*2 {
+		for i, e := range entries {
+			convertInto(e, records.At(i))
+		}
+		return logs
+	}
+
+	chunk := (len(entries) + workerCount - 1) / workerCount
+	var wg sync.WaitGroup
+	for start := 0; start < len(entries); start += chunk {
+		end := start + chunk
+		if end > len(entries) {
+			end = len(entries)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				convertInto(entries[i], records.At(i))
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return logs
+}
+
+// convertInto maps a single entry.Entry onto an existing plog.LogRecord.
+func convertInto(e *entry.Entry, dest plog.LogRecord) {
+	dest.SetTimestamp(pcommon.NewTimestampFromTime(e.Timestamp))
+	if e.Body != nil {
+		_ = dest.Body().FromRaw(e.Body)
+	}
+}
+
+// extractCheckpoints pairs each entry with whatever persister checkpoint
+// the operator that produced it attached (see checkpointKeyAttribute), in
+// the same order convertEntries appends them to the resulting plog.Logs,
+// so the result lines up one-to-one with recordCheckpoints as
+// CheckpointAccepted expects.
+func extractCheckpoints(entries []*entry.Entry) []map[string][]byte {
+	checkpoints := make([]map[string][]byte, len(entries))
+	for i, e := range entries {
+		checkpoints[i] = entryCheckpoint(e)
+	}
+	return checkpoints
+}
+
+// entryCheckpoint returns the single-key persister checkpoint e carries,
+// or an empty map if e isn't tied to one.
+func entryCheckpoint(e *entry.Entry) map[string][]byte {
+	key, ok := e.Attributes[checkpointKeyAttribute].(string)
+	if !ok || key == "" {
+		return map[string][]byte{}
+	}
+	value, _ := e.Attributes[checkpointValueAttribute].(string)
+	return map[string][]byte{key: []byte(value)}
+}
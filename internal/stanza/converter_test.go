@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConvertEntriesPreservesOrderAcrossWorkers guards against the
+// parallel path in convertEntries scrambling records between goroutines
+// or losing any of them - each entry's body must land at the same index
+// it would have under a single worker.
+func TestConvertEntriesPreservesOrderAcrossWorkers(t *testing.T) {
+	const n = 50
+	entries := make([]*entry.Entry, n)
+	for i := range entries {
+		entries[i] = &entry.Entry{Timestamp: time.Now(), Body: i}
+	}
+
+	logs := convertEntries(entries, 4)
+
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, n, records.Len())
+	for i := 0; i < n; i++ {
+		require.EqualValues(t, i, records.At(i).Body().AsRaw())
+	}
+}
+
+func TestConvertEntriesEmptyBatchHasNoResourceLogs(t *testing.T) {
+	logs := convertEntries(nil, 4)
+	require.Equal(t, 0, logs.ResourceLogs().Len())
+}
@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// deadLetterSink is where permanently-failed plog.Logs batches go: a
+// secondary consumer.Logs when the receiver is wired up with one, or a
+// persister key prefix (BaseConfig.DeadLetterStorage) otherwise, so an
+// operator or external tool can inspect or replay what the primary
+// pipeline could not deliver. Neither is required; with neither set, a
+// permanent failure is just dropped (the pre-existing behavior).
+//
+// A single deadLetterSink is shared across every logsRoute a receiver
+// drives (and the retryQueue callbacks for each of them), so concurrent
+// Send calls are expected; mu serializes appendToPersister's read-modify
+// -write of the manifest so two permanent failures landing at the same
+// time can't race each other into allocating the same batch key or
+// clobbering one another's Keys append.
+type deadLetterSink struct {
+	secondary consumer.Logs
+	persister *persister
+	key       string
+
+	mu sync.Mutex
+}
+
+// deadLetterManifest lists the persister keys under a deadLetterSink's
+// key prefix that currently hold dead-lettered batches, and the next
+// sequence number to allocate, so every Send call appends a new batch
+// instead of overwriting the previous one.
+type deadLetterManifest struct {
+	Keys []string `json:"keys"`
+	Next int      `json:"next"`
+}
+
+// Send hands logs to whichever sink is configured. cause is only used in
+// the error returned when no sink is configured; it is not stored with a
+// batch written to the secondary consumer or the persister.
+func (d *deadLetterSink) Send(ctx context.Context, logs plog.Logs, cause error) error {
+	if d == nil {
+		return fmt.Errorf("dropped batch, no dead letter sink configured: %w", cause)
+	}
+	if d.secondary != nil {
+		return d.secondary.ConsumeLogs(ctx, logs)
+	}
+	if d.persister != nil && d.key != "" {
+		return d.appendToPersister(ctx, logs)
+	}
+	return fmt.Errorf("dropped batch, no dead letter sink configured: %w", cause)
+}
+
+// appendToPersister writes logs under a fresh, sequence-numbered key
+// derived from d.key and records that key in d.key's manifest, so
+// repeated permanent failures accumulate as distinct, replayable batches
+// rather than clobbering one another. Serialized by d.mu: the manifest
+// read-modify-write below isn't safe to interleave across the concurrent
+// callers a shared deadLetterSink actually has.
+func (d *deadLetterSink) appendToPersister(ctx context.Context, logs plog.Logs) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	if err != nil {
+		return fmt.Errorf("marshal dead letter batch: %w", err)
+	}
+
+	manifest, err := d.readManifest(ctx)
+	if err != nil {
+		return fmt.Errorf("read dead letter manifest: %w", err)
+	}
+
+	batchKey := fmt.Sprintf("%s/%d", d.key, manifest.Next)
+	if err := d.persister.Set(ctx, batchKey, data); err != nil {
+		return fmt.Errorf("write dead letter batch: %w", err)
+	}
+
+	manifest.Keys = append(manifest.Keys, batchKey)
+	manifest.Next++
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("marshal dead letter manifest: %w", err)
+	}
+	return d.persister.Set(ctx, d.key, raw)
+}
+
+func (d *deadLetterSink) readManifest(ctx context.Context) (deadLetterManifest, error) {
+	raw, err := d.persister.Get(ctx, d.key)
+	if err != nil {
+		return deadLetterManifest{}, err
+	}
+	if len(raw) == 0 {
+		return deadLetterManifest{}, nil
+	}
+	var manifest deadLetterManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return deadLetterManifest{}, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestDeadLetterSinkPrefersSecondaryConsumer(t *testing.T) {
+	secondary := &consumertest.LogsSink{}
+	sink := &deadLetterSink{secondary: secondary, persister: newMockPersister(), key: "dead-letter"}
+
+	require.NoError(t, sink.Send(context.Background(), plog.NewLogs(), errors.New("boom")))
+	require.Len(t, secondary.AllLogs(), 1)
+}
+
+func TestDeadLetterSinkFallsBackToPersister(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+	sink := &deadLetterSink{persister: p, key: "dead-letter"}
+
+	require.NoError(t, sink.Send(ctx, plog.NewLogs(), errors.New("boom")))
+
+	manifest, err := sink.readManifest(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifest.Keys, 1)
+
+	v, err := p.Get(ctx, manifest.Keys[0])
+	require.NoError(t, err)
+	require.NotEmpty(t, v)
+}
+
+// TestDeadLetterSinkAppendsRatherThanOverwrites guards against a second
+// permanently-failed batch clobbering the first - only the manifest's
+// last entry would survive if Send reused a single fixed key.
+func TestDeadLetterSinkAppendsRatherThanOverwrites(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+	sink := &deadLetterSink{persister: p, key: "dead-letter"}
+
+	require.NoError(t, sink.Send(ctx, plog.NewLogs(), errors.New("first")))
+	require.NoError(t, sink.Send(ctx, plog.NewLogs(), errors.New("second")))
+
+	manifest, err := sink.readManifest(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifest.Keys, 2)
+	require.NotEqual(t, manifest.Keys[0], manifest.Keys[1])
+
+	for _, key := range manifest.Keys {
+		v, err := p.Get(ctx, key)
+		require.NoError(t, err)
+		require.NotEmpty(t, v)
+	}
+}
+
+// TestDeadLetterSinkSendIsSafeForConcurrentCallers guards against a race
+// in appendToPersister's manifest read-modify-write: a receiver shares one
+// deadLetterSink across every logsRoute it drives, so concurrent permanent
+// failures are the normal case, not an edge case. Without serialization,
+// two concurrent Sends can read the same manifest, allocate the same
+// batch key, and have one of them disappear off the manifest entirely.
+func TestDeadLetterSinkSendIsSafeForConcurrentCallers(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+	sink := &deadLetterSink{persister: p, key: "dead-letter"}
+
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			require.NoError(t, sink.Send(ctx, plog.NewLogs(), errors.New("boom")))
+		}()
+	}
+	wg.Wait()
+
+	manifest, err := sink.readManifest(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifest.Keys, n)
+
+	seen := make(map[string]bool, n)
+	for _, key := range manifest.Keys {
+		require.False(t, seen[key], "duplicate dead letter key %q", key)
+		seen[key] = true
+	}
+}
+
+func TestDeadLetterSinkDropsWithoutSink(t *testing.T) {
+	sink := &deadLetterSink{}
+	err := sink.Send(context.Background(), plog.NewLogs(), errors.New("boom"))
+	require.Error(t, err)
+}
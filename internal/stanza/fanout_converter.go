@@ -0,0 +1,109 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+)
+
+// fanoutConverter lets a single stanza receiver instance - one operator
+// pipeline, one set of file offsets - feed several downstream pipelines.
+// Each configured consumer gets its own Converter, so flush tuning, queue
+// depth, and overflow policy are all independent. A consumer with
+// DropOnOverflow never holds up the others; one without it can delay the
+// next Batch call for every consumer (see Batch).
+type fanoutConverter struct {
+	converters []*Converter
+}
+
+// newFanoutConverter builds one Converter per entry in consumerCfgs. If
+// consumerCfgs is empty, it falls back to a single Converter built from
+// fallback, matching a receiver configured without an explicit Consumers
+// block.
+func newFanoutConverter(fallback ConverterConfig, consumerCfgs []ConsumerConfig) *fanoutConverter {
+	if len(consumerCfgs) == 0 {
+		return &fanoutConverter{converters: []*Converter{NewConverter(fallback)}}
+	}
+
+	converters := make([]*Converter, len(consumerCfgs))
+	for i, cfg := range consumerCfgs {
+		converters[i] = newConverterForConsumer(cfg)
+	}
+	return &fanoutConverter{converters: converters}
+}
+
+// Stop tears down every underlying Converter, closing its output channel.
+func (f *fanoutConverter) Stop() {
+	for _, c := range f.converters {
+		c.Stop()
+	}
+}
+
+// Batch dispatches entries to every underlying Converter concurrently.
+// Each Converter receives its own logical copy of the batch - entries are
+// never mutated downstream of Batch, so handing the same slice to every
+// Converter is safe and avoids a real per-consumer deep copy until a
+// Converter actually flushes into a plog.Logs of its own, at which point
+// it builds its own resource/scope/record tree sharing nothing with its
+// siblings.
+//
+// Dispatch has to be concurrent, not just backpressure-isolated: a
+// consumer without DropOnOverflow blocks inside its own Converter.Batch
+// until its queue drains (see Converter.flush), and a sequential loop
+// would let that block delay every consumer dispatched after it. Running
+// each Converter's Batch call in its own goroutine means a blocking
+// consumer does not keep its siblings from receiving the current batch.
+// Batch itself returns only after every Converter's call has returned, so
+// while one consumer stays blocked, no further batch reaches any consumer.
+func (f *fanoutConverter) Batch(entries []*entry.Entry) error {
+	return f.dispatch(func(c *Converter) error {
+		return c.Batch(entries)
+	})
+}
+
+// Flush flushes every underlying Converter's pending batch concurrently,
+// same rationale as Batch.
+func (f *fanoutConverter) Flush() error {
+	return f.dispatch(func(c *Converter) error {
+		return c.Flush(context.Background())
+	})
+}
+
+func (f *fanoutConverter) dispatch(call func(*Converter) error) error {
+	errs := make([]error, len(f.converters))
+	var wg sync.WaitGroup
+	wg.Add(len(f.converters))
+	for i, c := range f.converters {
+		i, c := i, c
+		go func() {
+			defer wg.Done()
+			if err := call(c); err != nil {
+				errs[i] = fmt.Errorf("consumer %d: %w", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFanoutConverterFallsBackToSingleConverter(t *testing.T) {
+	f := newFanoutConverter(ConverterConfig{MaxFlushCount: 1}, nil)
+	require.Len(t, f.converters, 1)
+}
+
+func TestNewFanoutConverterHonorsQueueSize(t *testing.T) {
+	f := newFanoutConverter(ConverterConfig{}, []ConsumerConfig{
+		{Converter: ConverterConfig{MaxFlushCount: 1}, QueueSize: 3},
+		{Converter: ConverterConfig{MaxFlushCount: 1}},
+	})
+	defer f.Stop()
+
+	require.Equal(t, 3, cap(f.converters[0].outChan))
+	require.Equal(t, defaultOutChannelSize, cap(f.converters[1].outChan))
+}
+
+func TestFanoutConverterDispatchesToEveryConsumer(t *testing.T) {
+	f := newFanoutConverter(ConverterConfig{}, []ConsumerConfig{
+		{Converter: ConverterConfig{MaxFlushCount: 1}},
+		{Converter: ConverterConfig{MaxFlushCount: 1}},
+	})
+	defer f.Stop()
+
+	require.NoError(t, f.Batch([]*entry.Entry{{Timestamp: time.Now()}}))
+
+	for i, c := range f.converters {
+		select {
+		case <-c.OutChannel():
+		case <-time.After(time.Second):
+			t.Fatalf("consumer %d never received a flush", i)
+		}
+	}
+}
+
+// TestFanoutConverterIsolatesOverflowingConsumer verifies that a consumer
+// configured with DropOnOverflow cannot stall its siblings: once its
+// output channel is full it drops the oldest queued plog.Logs instead of
+// blocking Batch, so an un-drained sibling consumer keeps receiving
+// flushes on every call.
+func TestFanoutConverterIsolatesOverflowingConsumer(t *testing.T) {
+	f := newFanoutConverter(ConverterConfig{}, []ConsumerConfig{
+		{Converter: ConverterConfig{MaxFlushCount: 1}, DropOnOverflow: true},
+		{Converter: ConverterConfig{MaxFlushCount: 1}, DropOnOverflow: true},
+	})
+	defer f.Stop()
+
+	slow, fast := f.converters[0], f.converters[1]
+
+	// Overflow the slow consumer's output channel without ever draining
+	// it; drain the fast consumer's every time so it never itself
+	// overflows.
+	for i := 0; i < defaultOutChannelSize+1; i++ {
+		require.NoError(t, f.Batch([]*entry.Entry{{Timestamp: time.Now()}}))
+		select {
+		case <-fast.OutChannel():
+		case <-time.After(time.Second):
+			t.Fatalf("fast consumer stalled on batch %d because the slow consumer overflowed", i)
+		}
+	}
+
+	require.Len(t, slow.outChan, defaultOutChannelSize)
+}
+
+// TestFanoutConverterBlockingConsumerDoesNotStallSiblings covers the
+// DropOnOverflow: false path, which drops nothing and instead blocks
+// inside that consumer's own Converter.Batch call until its queue
+// drains. Dispatch must run each consumer concurrently so that block
+// only affects its own goroutine, not consumers dispatched after it.
+func TestFanoutConverterBlockingConsumerDoesNotStallSiblings(t *testing.T) {
+	f := newFanoutConverter(ConverterConfig{}, []ConsumerConfig{
+		{Converter: ConverterConfig{MaxFlushCount: 1}, DropOnOverflow: false},
+		{Converter: ConverterConfig{MaxFlushCount: 1}, DropOnOverflow: false},
+	})
+	defer f.Stop()
+
+	blocking, fast := f.converters[0], f.converters[1]
+
+	// Fill the blocking consumer's channel without draining it, draining
+	// the fast one each time so it never itself backs up.
+	for i := 0; i < defaultOutChannelSize; i++ {
+		require.NoError(t, f.Batch([]*entry.Entry{{Timestamp: time.Now()}}))
+		select {
+		case <-fast.OutChannel():
+		case <-time.After(time.Second):
+			t.Fatalf("fast consumer stalled while filling the blocking consumer's queue, batch %d", i)
+		}
+	}
+	require.Len(t, blocking.outChan, defaultOutChannelSize)
+
+	// The blocking consumer's queue is now full, so dispatching to it
+	// will block inside its Converter.Batch call. The fast consumer must
+	// still receive this batch promptly instead of waiting for the
+	// blocked one.
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Batch([]*entry.Entry{{Timestamp: time.Now()}})
+	}()
+
+	select {
+	case <-fast.OutChannel():
+	case <-time.After(time.Second):
+		t.Fatal("fast consumer stalled behind a blocking sibling consumer")
+	}
+
+	// Drain the blocking consumer so its goroutine (and the test) can
+	// finish cleanly.
+	select {
+	case <-blocking.OutChannel():
+	case <-time.After(time.Second):
+		t.Fatal("blocking consumer never flushed once drained")
+	}
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Batch never returned after the blocking consumer was drained")
+	}
+}
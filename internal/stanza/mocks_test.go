@@ -91,7 +91,13 @@ type TestConfig struct {
 	BaseConfig `mapstructure:",squash"`
 	Input      InputConfig `mapstructure:",remain"`
 }
-type TestReceiverType struct{}
+// TestReceiverType is a ReceiverType usable in tests. Signals lets a test
+// opt the receiver it builds into SignalProfiles in addition to the
+// default SignalLogs, e.g. TestReceiverType{Signals: []Signal{SignalLogs,
+// SignalProfiles}}.
+type TestReceiverType struct {
+	Signals []Signal
+}
 
 func (f TestReceiverType) Type() config.Type {
 	return testType
@@ -102,10 +108,15 @@ func (f TestReceiverType) CreateDefaultConfig() config.Receiver {
 		BaseConfig: BaseConfig{
 			ReceiverSettings: config.NewReceiverSettings(config.NewComponentID(testType)),
 			Operators:        OperatorConfigs{},
+			Signals:          f.Signals,
 			Converter: ConverterConfig{
 				MaxFlushCount: 1,
 				FlushInterval: 100 * time.Millisecond,
 			},
+			Profiles: ConverterConfig{
+				MaxFlushCount: 1,
+				FlushInterval: 100 * time.Millisecond,
+			},
 		},
 		Input: InputConfig{},
 	}
@@ -131,22 +142,61 @@ func (f TestReceiverType) DecodeInputConfig(cfg config.Receiver) (*operator.Conf
 }
 
 func newMockPersister() *persister {
-	return &persister{
-		client: newMockClient(),
+	return newMockPersisterOnClient(newMockClient())
+}
+
+// newMockPersisterOnClient builds a persister over an existing
+// mockClient, so tests can simulate a restart by constructing a fresh
+// persister on top of the same backing store.
+func newMockPersisterOnClient(client *mockClient) *persister {
+	p, err := newPersister(client)
+	if err != nil {
+		panic(err)
 	}
+	return p
 }
 
 type mockClient struct {
 	cache    map[string][]byte
 	cacheMux sync.Mutex
+
+	// failAfterCalls, when >= 0, makes the mockClient return errInjected
+	// once it has completed that many Set/Batch calls, instead of
+	// applying the call's writes. It lets tests simulate a crash at a
+	// specific point in persister.ReplaceAll's stage/swap/promote
+	// sequence and assert that the cache is left in a consistent state.
+	failAfterCalls int
+	calls          int
 }
 
+var errInjected = errors.New("injected failure")
+
 func newMockClient() *mockClient {
 	return &mockClient{
-		cache: make(map[string][]byte),
+		cache:          make(map[string][]byte),
+		failAfterCalls: -1,
 	}
 }
 
+// failingAfter returns a mockClient whose first n calls to Set or Batch
+// succeed and every later one fails instead of applying its writes,
+// simulating a crash partway through a staged write.
+func failingAfter(n int) *mockClient {
+	c := newMockClient()
+	c.failAfterCalls = n
+	return c
+}
+
+// shouldFail reports whether the call being made should be rejected,
+// without applying any writes. Caller must hold cacheMux.
+func (p *mockClient) shouldFail() bool {
+	if p.failAfterCalls < 0 {
+		return false
+	}
+	p.calls++
+	return p.calls > p.failAfterCalls
+}
+
 func (p *mockClient) Get(_ context.Context, key string) ([]byte, error) {
 	p.cacheMux.Lock()
 	defer p.cacheMux.Unlock()
@@ -156,6 +206,9 @@ func (p *mockClient) Get(_ context.Context, key string) ([]byte, error) {
 func (p *mockClient) Set(_ context.Context, key string, value []byte) error {
 	p.cacheMux.Lock()
 	defer p.cacheMux.Unlock()
+	if p.shouldFail() {
+		return errInjected
+	}
 	p.cache[key] = value
 	return nil
 }
@@ -170,6 +223,9 @@ func (p *mockClient) Delete(_ context.Context, key string) error {
 func (p *mockClient) Batch(_ context.Context, ops ...storage.Operation) error {
 	p.cacheMux.Lock()
 	defer p.cacheMux.Unlock()
+	if p.shouldFail() {
+		return errInjected
+	}
 
 	for _, op := range ops {
 		switch op.Type {
@@ -0,0 +1,241 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+)
+
+// persisterManifestKey is the single key that is rewritten atomically to
+// publish a new snapshot written by ReplaceAll. Readers that observe a
+// manifest always observe a complete, consistent key set; they never see a
+// half-written batch.
+const persisterManifestKey = "__stanza_manifest"
+
+// persisterPrevManifestKey durably records the manifest a ReplaceAll call
+// is about to replace, written in the same stage step as the new
+// snapshot's values and cleared once that call's promote step finishes.
+// Its presence on restart is what tells recover a ReplaceAll committed
+// its swap but never finished deleting the previous snapshot's stale
+// keys - the new manifest alone only carries the keys to promote, not the
+// keys that need deleting, because the old key list it replaced is gone
+// by the time recover runs.
+const persisterPrevManifestKey = "__stanza_prev_manifest"
+
+// stagingPrefix namespaces the keys that ReplaceAll writes before it
+// commits the manifest. Entries under this prefix are scratch space: a
+// crash before the manifest is rewritten leaves them orphaned but never
+// visible to Get, so recovery always starts from the last good snapshot.
+const stagingPrefix = "__staging/"
+
+// persister implements operator.Persister on top of a storage.Client,
+// giving stanza operators (e.g. file_input) a place to checkpoint state
+// such as read offsets.
+type persister struct {
+	client storage.Client
+}
+
+// newPersister wraps client in a persister, first finishing any
+// ReplaceAll snapshot that committed its manifest (the atomic swap) but
+// never ran its promote step - e.g. because the process crashed between
+// the two. Without this, Get would keep returning pre-snapshot values
+// forever after a restart even though the manifest already points at the
+// newer snapshot.
+func newPersister(client storage.Client) (*persister, error) {
+	p := &persister{client: client}
+	if err := p.recover(context.Background()); err != nil {
+		return nil, fmt.Errorf("recover persister: %w", err)
+	}
+	return p, nil
+}
+
+// recover finishes any ReplaceAll that committed its manifest swap but
+// was interrupted before its promote step completed, in both halves that
+// promote does: re-promoting any new key still sitting under its staging
+// key, and deleting any previous-snapshot key that isn't in the new
+// manifest. The second half is only needed - and only possible - when
+// persisterPrevManifestKey is still present: its absence means either no
+// ReplaceAll has ever run, or the last one's promote step cleared it on
+// completion, so there is nothing left to finish.
+func (p *persister) recover(ctx context.Context) error {
+	manifest, err := p.readManifest(ctx)
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+
+	var ops []storage.Operation
+	for _, key := range manifest.Keys {
+		staged, err := p.client.Get(ctx, stagingKey(key))
+		if err != nil {
+			return fmt.Errorf("read staged value for %q: %w", key, err)
+		}
+		if staged == nil {
+			// Either already promoted, or this key predates staging -
+			// either way there's nothing to finish for it.
+			continue
+		}
+		ops = append(ops, *storage.SetOperation(key, staged))
+		ops = append(ops, *storage.DeleteOperation(stagingKey(key)))
+	}
+
+	prev, pending, err := p.readManifestAt(ctx, persisterPrevManifestKey)
+	if err != nil {
+		return fmt.Errorf("read previous manifest: %w", err)
+	}
+	if pending {
+		current := make(map[string]struct{}, len(manifest.Keys))
+		for _, key := range manifest.Keys {
+			current[key] = struct{}{}
+		}
+		for _, key := range prev.Keys {
+			if _, ok := current[key]; !ok {
+				ops = append(ops, *storage.DeleteOperation(key))
+			}
+		}
+		ops = append(ops, *storage.DeleteOperation(persisterPrevManifestKey))
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+	return p.client.Batch(ctx, ops...)
+}
+
+func (p *persister) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.client.Get(ctx, key)
+}
+
+func (p *persister) Set(ctx context.Context, key string, value []byte) error {
+	return p.client.Set(ctx, key, value)
+}
+
+func (p *persister) Delete(ctx context.Context, key string) error {
+	return p.client.Delete(ctx, key)
+}
+
+// persisterManifest records which logical keys make up a snapshot written
+// by ReplaceAll, so a later call knows which previously-persisted keys
+// have been dropped from the new state and can reclaim them.
+type persisterManifest struct {
+	Keys []string `json:"keys"`
+}
+
+// ReplaceAll atomically replaces the full set of persisted keys with
+// state. Unlike Set, which writes a single key in place, ReplaceAll gives
+// callers that checkpoint a whole snapshot at once a guarantee that a
+// crash mid write never leaves Get returning a mix of old and new values.
+// The stanza receiver does not use it; see CheckpointAccepted.
+//
+// It proceeds in three steps:
+//  1. stage: every key in state is written under stagingPrefix, alongside
+//     the previous manifest written durably to persisterPrevManifestKey.
+//     For durable storage.Client implementations this is the point at
+//     which the data hits disk. Recording the previous manifest here,
+//     rather than only holding it in memory, is what lets recover finish
+//     step 3's stale-key deletion after a crash - by the time recover
+//     runs, the current manifest only has the new keys, not the old ones
+//     it replaced.
+//  2. swap: a manifest listing the staged keys is written to
+//     persisterManifestKey in a single call. This is the atomic
+//     publication point - a crash before it leaves the previous snapshot
+//     fully intact, and a crash after it means the new snapshot is
+//     durable even if promotion below hasn't run yet.
+//  3. promote: staged values are copied to their real keys, stale keys
+//     from the previous manifest that are absent from state are deleted,
+//     the staging entries are cleaned up, and persisterPrevManifestKey is
+//     cleared to mark this ReplaceAll as fully complete.
+func (p *persister) ReplaceAll(ctx context.Context, state map[string][]byte) error {
+	prev, err := p.readManifest(ctx)
+	if err != nil {
+		return fmt.Errorf("read previous manifest: %w", err)
+	}
+	prevRaw, err := json.Marshal(prev)
+	if err != nil {
+		return fmt.Errorf("marshal previous manifest: %w", err)
+	}
+
+	keys := make([]string, 0, len(state))
+	stage := make([]storage.Operation, 0, len(state)+1)
+	for key, value := range state {
+		keys = append(keys, key)
+		stage = append(stage, *storage.SetOperation(stagingKey(key), value))
+	}
+	stage = append(stage, *storage.SetOperation(persisterPrevManifestKey, prevRaw))
+	if err := p.client.Batch(ctx, stage...); err != nil {
+		return fmt.Errorf("stage snapshot: %w", err)
+	}
+
+	manifest, err := json.Marshal(persisterManifest{Keys: keys})
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	// This Set is the atomic swap: once it returns, the new snapshot is
+	// the one recovery should trust, regardless of whether promotion
+	// below completes.
+	if err := p.client.Set(ctx, persisterManifestKey, manifest); err != nil {
+		return fmt.Errorf("commit manifest: %w", err)
+	}
+
+	promote := make([]storage.Operation, 0, 2*len(state)+len(prev.Keys)+1)
+	for key, value := range state {
+		promote = append(promote, *storage.SetOperation(key, value))
+		promote = append(promote, *storage.DeleteOperation(stagingKey(key)))
+	}
+	for _, key := range prev.Keys {
+		if _, ok := state[key]; !ok {
+			promote = append(promote, *storage.DeleteOperation(key))
+		}
+	}
+	promote = append(promote, *storage.DeleteOperation(persisterPrevManifestKey))
+	if err := p.client.Batch(ctx, promote...); err != nil {
+		return fmt.Errorf("promote snapshot: %w", err)
+	}
+	return nil
+}
+
+func (p *persister) readManifest(ctx context.Context) (persisterManifest, error) {
+	manifest, _, err := p.readManifestAt(ctx, persisterManifestKey)
+	return manifest, err
+}
+
+// readManifestAt reads and unmarshals the persisterManifest stored at key,
+// also reporting whether anything was stored there at all - distinct from
+// an empty-but-present manifest, which recover relies on to tell a
+// completed ReplaceAll (persisterPrevManifestKey cleared) from one that
+// crashed with nothing left to reconcile (persisterPrevManifestKey held
+// an empty manifest).
+func (p *persister) readManifestAt(ctx context.Context, key string) (persisterManifest, bool, error) {
+	raw, err := p.client.Get(ctx, key)
+	if err != nil {
+		return persisterManifest{}, false, err
+	}
+	if len(raw) == 0 {
+		return persisterManifest{}, false, nil
+	}
+	var manifest persisterManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return persisterManifest{}, false, err
+	}
+	return manifest, true, nil
+}
+
+func stagingKey(key string) string {
+	return stagingPrefix + key
+}
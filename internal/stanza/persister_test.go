@@ -0,0 +1,158 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersisterReplaceAll(t *testing.T) {
+	ctx := context.Background()
+	p := newMockPersister()
+
+	require.NoError(t, p.Set(ctx, "offset-a", []byte("1")))
+	require.NoError(t, p.Set(ctx, "offset-b", []byte("2")))
+
+	require.NoError(t, p.ReplaceAll(ctx, map[string][]byte{
+		"offset-a": []byte("10"),
+		"offset-c": []byte("30"),
+	}))
+
+	a, err := p.Get(ctx, "offset-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), a)
+
+	c, err := p.Get(ctx, "offset-c")
+	require.NoError(t, err)
+	require.Equal(t, []byte("30"), c)
+
+	// offset-b was not part of the new snapshot, so it must be gone.
+	b, err := p.Get(ctx, "offset-b")
+	require.NoError(t, err)
+	require.Nil(t, b)
+}
+
+// TestPersisterReplaceAllCrashBeforeSwap simulates a crash while the new
+// snapshot is still being staged. Because the manifest was never
+// rewritten, the previous snapshot must remain exactly as it was.
+func TestPersisterReplaceAllCrashBeforeSwap(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient()
+	p := &persister{client: client}
+
+	require.NoError(t, p.Set(ctx, "offset-a", []byte("1")))
+
+	client.failAfterCalls = 0
+	err := p.ReplaceAll(ctx, map[string][]byte{"offset-a": []byte("10")})
+	require.Error(t, err)
+
+	a, err := p.Get(ctx, "offset-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), a)
+}
+
+// TestPersisterReplaceAllCrashAfterSwap simulates a crash after the
+// manifest has been rewritten (the atomic swap point) but before the new
+// values have been promoted to their real keys. The manifest reflects the
+// new snapshot even though promotion did not finish. Unlike a second
+// ReplaceAll call in the same process, an actual crash means nothing ever
+// calls ReplaceAll again - recovery has to happen on restart, so this
+// simulates that by building a brand new persister over the same
+// backing client rather than reusing p.
+func TestPersisterReplaceAllCrashAfterSwap(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient()
+	p := &persister{client: client}
+
+	require.NoError(t, p.Set(ctx, "offset-a", []byte("1")))
+
+	state := map[string][]byte{"offset-a": []byte("10")}
+
+	client.failAfterCalls = 2 // stage succeeds, manifest swap succeeds, promote fails
+	require.Error(t, p.ReplaceAll(ctx, state))
+
+	manifest, err := p.readManifest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"offset-a"}, manifest.Keys)
+
+	// Simulate a restart: a fresh persister over the same client, with no
+	// ReplaceAll call in between.
+	client.failAfterCalls = -1
+	restarted := newMockPersisterOnClient(client)
+
+	a, err := restarted.Get(ctx, "offset-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), a)
+}
+
+// TestPersisterReplaceAllRecoversStaleKeyDeletionAfterCrash simulates a
+// crash after the manifest swap but before promote, on a ReplaceAll call
+// that also drops a key from the previous snapshot. recover must not only
+// finish promoting the new snapshot's keys but also finish deleting the
+// stale key the crashed promote never got to - otherwise Get keeps
+// returning its pre-snapshot value forever, contradicting ReplaceAll's
+// "atomically replaces the full set of persisted keys" guarantee.
+func TestPersisterReplaceAllRecoversStaleKeyDeletionAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient()
+	p := newMockPersisterOnClient(client)
+
+	require.NoError(t, p.ReplaceAll(ctx, map[string][]byte{
+		"offset-a": []byte("1"),
+		"offset-b": []byte("2"),
+	}))
+
+	// mockClient only counts Set/Batch calls while failure injection is
+	// enabled, so client.calls is still 0 after the snapshot above; let
+	// the next 2 calls of the following ReplaceAll (stage, swap) succeed
+	// and fail its 3rd (promote).
+	client.failAfterCalls = client.calls + 2
+	require.Error(t, p.ReplaceAll(ctx, map[string][]byte{"offset-a": []byte("10")}))
+
+	manifest, err := p.readManifest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"offset-a"}, manifest.Keys)
+
+	// Simulate a restart: a fresh persister over the same client.
+	client.failAfterCalls = -1
+	restarted := newMockPersisterOnClient(client)
+
+	a, err := restarted.Get(ctx, "offset-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), a)
+
+	b, err := restarted.Get(ctx, "offset-b")
+	require.NoError(t, err)
+	require.Nil(t, b)
+}
+
+// TestPersisterRecoverIsNoopWhenAlreadyPromoted verifies that recovering
+// a persister whose last ReplaceAll completed cleanly doesn't touch
+// anything.
+func TestPersisterRecoverIsNoopWhenAlreadyPromoted(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient()
+	p := newMockPersisterOnClient(client)
+
+	require.NoError(t, p.ReplaceAll(ctx, map[string][]byte{"offset-a": []byte("10")}))
+
+	restarted := newMockPersisterOnClient(client)
+	a, err := restarted.Get(ctx, "offset-a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), a)
+}
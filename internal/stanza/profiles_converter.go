@@ -0,0 +1,321 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// Schema keys a pprof-shaped entry.Entry body is expected to carry. An
+// operator like file_input tailing a raw pprof/eBPF dump is responsible
+// for decoding it into a map shaped like this before it reaches
+// ProfilesConverter; ProfilesConverter only understands this shape, not
+// the wire format it came from.
+const (
+	pprofFieldSampleType = "sample_type"
+	pprofFieldSamples    = "samples"
+	pprofFieldLocations  = "location"
+	pprofFieldMappings   = "mapping"
+	pprofFieldStrings    = "string_table"
+)
+
+// ProfilesConsumer is the consumer.Profiles peer of consumer.Logs: the
+// interface a downstream component implements to receive the
+// pprofile.Profiles records ProfilesConverter emits. The collector's
+// consumer package has no profiles signal of its own yet, so a receiver
+// wanting to emit profiles depends on this interface directly instead.
+type ProfilesConsumer interface {
+	ConsumeProfiles(ctx context.Context, profiles pprofile.Profiles) error
+}
+
+// IsProfileEntry reports whether e's body matches the pprof schema
+// ProfilesConverter expects, as opposed to a plain log record. Receivers
+// that accept both SignalLogs and SignalProfiles use this to route each
+// entry to the right converter.
+func IsProfileEntry(e *entry.Entry) bool {
+	body, ok := e.Body.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasSampleType := body[pprofFieldSampleType]
+	_, hasSamples := body[pprofFieldSamples]
+	return hasSampleType && hasSamples
+}
+
+// ProfilesConverter is the pprofile.Profiles peer of Converter: it batches
+// entry.Entry values whose body matches the pprof schema (see
+// IsProfileEntry) and converts them into OTLP profile records, so
+// operators such as file_input can tail perf/pprof dumps or eBPF profiler
+// output through the same operator DSL used for logs.
+type ProfilesConverter struct {
+	maxFlushCount uint
+	flushInterval time.Duration
+
+	pending []*entry.Entry
+	outChan chan pprofile.Profiles
+}
+
+// NewProfilesConverter creates a ProfilesConverter from cfg.
+func NewProfilesConverter(cfg ConverterConfig) *ProfilesConverter {
+	return &ProfilesConverter{
+		maxFlushCount: cfg.MaxFlushCount,
+		flushInterval: cfg.FlushInterval,
+		outChan:       make(chan pprofile.Profiles, defaultOutChannelSize),
+	}
+}
+
+// OutChannel returns the channel that converted pprofile.Profiles are
+// emitted on.
+func (c *ProfilesConverter) OutChannel() <-chan pprofile.Profiles {
+	return c.outChan
+}
+
+// Batch appends entries to the pending buffer, flushing immediately if
+// MaxFlushCount is reached. Callers should have already filtered entries
+// with IsProfileEntry; Batch does not re-check.
+func (c *ProfilesConverter) Batch(entries []*entry.Entry) error {
+	c.pending = append(c.pending, entries...)
+	if c.maxFlushCount > 0 && uint(len(c.pending)) >= c.maxFlushCount {
+		return c.flush(context.Background())
+	}
+	return nil
+}
+
+// Flush converts and emits whatever entries are currently pending,
+// regardless of MaxFlushCount.
+func (c *ProfilesConverter) Flush(ctx context.Context) error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	return c.flush(ctx)
+}
+
+func (c *ProfilesConverter) flush(ctx context.Context) error {
+	profiles, err := convertProfileEntries(c.pending)
+	c.pending = nil
+	if err != nil {
+		return fmt.Errorf("convert profile entries: %w", err)
+	}
+
+	select {
+	case c.outChan <- profiles:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("flush: %w", ctx.Err())
+	}
+}
+
+// Stop closes the output channel, signalling downstream readers that no
+// further pprofile.Profiles will be emitted.
+func (c *ProfilesConverter) Stop() {
+	close(c.outChan)
+}
+
+// convertProfileEntries builds a single pprofile.Profiles out of a batch
+// of pprof-shaped entry.Entry values, one OTLP profile record per entry.
+func convertProfileEntries(entries []*entry.Entry) (pprofile.Profiles, error) {
+	profiles := pprofile.NewProfiles()
+	if len(entries) == 0 {
+		return profiles, nil
+	}
+	rp := profiles.ResourceProfiles().AppendEmpty()
+	sp := rp.ScopeProfiles().AppendEmpty()
+	for _, e := range entries {
+		if err := convertProfileInto(e, sp.Profiles().AppendEmpty()); err != nil {
+			return pprofile.Profiles{}, err
+		}
+	}
+	return profiles, nil
+}
+
+// convertProfileInto maps a single pprof-shaped entry.Entry onto an
+// existing pprofile.Profile: its sample_type, samples, location table,
+// mapping table, and string table are copied into the record's
+// corresponding OTLP dictionaries.
+func convertProfileInto(e *entry.Entry, dest pprofile.Profile) error {
+	body, ok := e.Body.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("profile entry body is %T, not map[string]interface{}", e.Body)
+	}
+
+	dest.SetTime(pcommon.NewTimestampFromTime(e.Timestamp))
+
+	for _, s := range toStringSlice(body[pprofFieldStrings]) {
+		dest.StringTable().Append(s)
+	}
+
+	for _, st := range toMapSlice(body[pprofFieldSampleType]) {
+		sampleType := dest.SampleType().AppendEmpty()
+		sampleType.SetTypeStrindex(int32Field(st, "type"))
+		sampleType.SetUnitStrindex(int32Field(st, "unit"))
+	}
+
+	for _, m := range toMapSlice(body[pprofFieldMappings]) {
+		mapping := dest.MappingTable().AppendEmpty()
+		mapping.SetMemoryStart(uint64(int64Field(m, "memory_start")))
+		mapping.SetMemoryLimit(uint64(int64Field(m, "memory_limit")))
+		mapping.SetFileOffset(uint64(int64Field(m, "file_offset")))
+		mapping.SetFilenameStrindex(int32Field(m, "filename"))
+	}
+
+	for _, l := range toMapSlice(body[pprofFieldLocations]) {
+		location := dest.LocationTable().AppendEmpty()
+		location.SetMappingIndex(int32Field(l, "mapping_index"))
+		location.SetAddress(uint64(int64Field(l, "address")))
+		for _, ln := range toMapSlice(l["line"]) {
+			line := location.Line().AppendEmpty()
+			line.SetFunctionIndex(int32Field(ln, "function_index"))
+			line.SetLine(int64Field(ln, "line"))
+		}
+	}
+
+	for _, s := range toMapSlice(body[pprofFieldSamples]) {
+		sample := dest.Sample().AppendEmpty()
+		for _, v := range toInt64Slice(s["value"]) {
+			sample.Value().Append(v)
+		}
+		for _, idx := range toInt32Slice(s["location_indices"]) {
+			sample.LocationIndices().Append(idx)
+		}
+	}
+
+	return nil
+}
+
+// toMapSlice, toStringSlice, toInt64Slice, and toInt32Slice normalize a
+// pprof-shaped field into the slice type convertProfileInto needs,
+// accepting both the Go-literal slice types this package's own tests
+// construct by hand and the []interface{} shape encoding/json produces
+// when an operator like file_input decodes a real pprof/eBPF dump into
+// entry.Entry.Body. Anything that isn't one of those two shapes - or an
+// element of the wrong type within a []interface{} - is dropped rather
+// than failing the whole entry.
+func toMapSlice(v interface{}) []map[string]interface{} {
+	switch vv := v.(type) {
+	case []map[string]interface{}:
+		return vv
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(vv))
+		for _, item := range vv {
+			if m, ok := item.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func toStringSlice(v interface{}) []string {
+	switch vv := v.(type) {
+	case []string:
+		return vv
+	case []interface{}:
+		out := make([]string, 0, len(vv))
+		for _, item := range vv {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func toInt64Slice(v interface{}) []int64 {
+	switch vv := v.(type) {
+	case []int64:
+		return vv
+	case []interface{}:
+		out := make([]int64, 0, len(vv))
+		for _, item := range vv {
+			if n, ok := toInt64(item); ok {
+				out = append(out, n)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func toInt32Slice(v interface{}) []int32 {
+	switch vv := v.(type) {
+	case []int32:
+		return vv
+	case []interface{}:
+		out := make([]int32, 0, len(vv))
+		for _, item := range vv {
+			if n, ok := toInt64(item); ok {
+				out = append(out, int32(n))
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// int32Field and int64Field read an expected-integer field out of a
+// pprof-shaped map, defaulting to zero when it's absent or not a
+// recognized numeric type rather than failing the whole entry over one
+// missing field.
+func int32Field(m map[string]interface{}, key string) int32 {
+	n, _ := toInt64(m[key])
+	return int32(n)
+}
+
+func int64Field(m map[string]interface{}, key string) int64 {
+	n, _ := toInt64(m[key])
+	return n
+}
+
+// toInt64 normalizes a numeric pprof field into an int64. encoding/json
+// decodes every JSON number into a float64 (or a json.Number, when the
+// decoder is configured with UseNumber) regardless of whether the
+// original value was integral, so a real decoded entry never produces the
+// int32/int64 literals this package's own tests construct by hand; every
+// numeric field has to tolerate both.
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil {
+			return int64(f), true
+		}
+		return 0, false
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsProfileEntry(t *testing.T) {
+	require.True(t, IsProfileEntry(&entry.Entry{Body: map[string]interface{}{
+		"sample_type": []map[string]interface{}{},
+		"samples":     []map[string]interface{}{},
+	}}))
+	require.False(t, IsProfileEntry(&entry.Entry{Body: "just a log line"}))
+	require.False(t, IsProfileEntry(&entry.Entry{Body: map[string]interface{}{"message": "no pprof fields"}}))
+}
+
+func TestProfilesConverterFlushesOnMaxFlushCount(t *testing.T) {
+	c := NewProfilesConverter(ConverterConfig{MaxFlushCount: 1})
+	defer c.Stop()
+
+	entries := []*entry.Entry{{
+		Timestamp: time.Now(),
+		Body: map[string]interface{}{
+			"sample_type": []map[string]interface{}{{"type": int32(0), "unit": int32(1)}},
+			"samples":     []map[string]interface{}{{}},
+		},
+	}}
+	require.NoError(t, c.Batch(entries))
+
+	select {
+	case profiles := <-c.OutChannel():
+		require.Equal(t, 1, profiles.ResourceProfiles().Len())
+	case <-time.After(time.Second):
+		t.Fatal("converter never flushed")
+	}
+}
+
+// TestProfilesConverterPopulatesLocationAndMappingTables guards against a
+// regression back to samples that carry no location, mapping, or value
+// data - structurally present pprofile.Profile records that are otherwise
+// functionally empty.
+func TestProfilesConverterPopulatesLocationAndMappingTables(t *testing.T) {
+	c := NewProfilesConverter(ConverterConfig{MaxFlushCount: 1})
+	defer c.Stop()
+
+	entries := []*entry.Entry{{
+		Timestamp: time.Now(),
+		Body: map[string]interface{}{
+			"sample_type": []map[string]interface{}{{"type": int32(0), "unit": int32(1)}},
+			"mapping": []map[string]interface{}{
+				{"memory_start": int64(0x1000), "memory_limit": int64(0x2000), "file_offset": int64(0), "filename": int32(2)},
+			},
+			"location": []map[string]interface{}{
+				{"mapping_index": int32(0), "address": int64(0x1234), "line": []map[string]interface{}{
+					{"function_index": int32(0), "line": int64(42)},
+				}},
+			},
+			"samples": []map[string]interface{}{
+				{"value": []int64{7}, "location_indices": []int32{0}},
+			},
+		},
+	}}
+	require.NoError(t, c.Batch(entries))
+
+	select {
+	case profiles := <-c.OutChannel():
+		profile := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0)
+		require.Equal(t, 1, profile.MappingTable().Len())
+		require.EqualValues(t, 0x1000, profile.MappingTable().At(0).MemoryStart())
+
+		require.Equal(t, 1, profile.LocationTable().Len())
+		require.Equal(t, 1, profile.LocationTable().At(0).Line().Len())
+		require.EqualValues(t, 42, profile.LocationTable().At(0).Line().At(0).Line())
+
+		require.Equal(t, 1, profile.Sample().Len())
+		require.Equal(t, 1, profile.Sample().At(0).Value().Len())
+		require.EqualValues(t, 7, profile.Sample().At(0).Value().At(0))
+		require.Equal(t, 1, profile.Sample().At(0).LocationIndices().Len())
+	case <-time.After(time.Second):
+		t.Fatal("converter never flushed")
+	}
+}
+
+// TestProfilesConverterPopulatesTablesFromJSONDecodedBody guards against a
+// regression to strict Go-literal type assertions: a body decoded off the
+// wire by encoding/json (the shape file_input would actually hand
+// ProfilesConverter after tailing a real pprof/eBPF dump) carries
+// []interface{} and float64 where this package's other tests construct
+// []map[string]interface{}, []int64, []int32, and int32/int64 literals by
+// hand, and must convert just the same.
+func TestProfilesConverterPopulatesTablesFromJSONDecodedBody(t *testing.T) {
+	c := NewProfilesConverter(ConverterConfig{MaxFlushCount: 1})
+	defer c.Stop()
+
+	raw := []byte(`{
+		"sample_type": [{"type": 0, "unit": 1}],
+		"mapping": [{"memory_start": 4096, "memory_limit": 8192, "file_offset": 0, "filename": 2}],
+		"location": [{"mapping_index": 0, "address": 4660, "line": [{"function_index": 0, "line": 42}]}],
+		"samples": [{"value": [7], "location_indices": [0]}]
+	}`)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &body))
+
+	require.NoError(t, c.Batch([]*entry.Entry{{Timestamp: time.Now(), Body: body}}))
+
+	select {
+	case profiles := <-c.OutChannel():
+		profile := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0)
+		require.Equal(t, 1, profile.MappingTable().Len())
+		require.EqualValues(t, 4096, profile.MappingTable().At(0).MemoryStart())
+
+		require.Equal(t, 1, profile.LocationTable().Len())
+		require.Equal(t, 1, profile.LocationTable().At(0).Line().Len())
+		require.EqualValues(t, 42, profile.LocationTable().At(0).Line().At(0).Line())
+
+		require.Equal(t, 1, profile.Sample().Len())
+		require.Equal(t, 1, profile.Sample().At(0).Value().Len())
+		require.EqualValues(t, 7, profile.Sample().At(0).Value().At(0))
+		require.Equal(t, 1, profile.Sample().At(0).LocationIndices().Len())
+	case <-time.After(time.Second):
+		t.Fatal("converter never flushed")
+	}
+}
+
+func TestProfilesConverterRejectsNonProfileBody(t *testing.T) {
+	c := NewProfilesConverter(ConverterConfig{MaxFlushCount: 1})
+	defer c.Stop()
+
+	err := c.Batch([]*entry.Entry{{Timestamp: time.Now(), Body: "not a profile"}})
+	require.Error(t, err)
+}
+
+func TestBaseConfigHasSignal(t *testing.T) {
+	defaultCfg := BaseConfig{}
+	require.True(t, defaultCfg.HasSignal(SignalLogs))
+	require.False(t, defaultCfg.HasSignal(SignalProfiles))
+
+	both := BaseConfig{Signals: []Signal{SignalLogs, SignalProfiles}}
+	require.True(t, both.HasSignal(SignalLogs))
+	require.True(t, both.HasSignal(SignalProfiles))
+}
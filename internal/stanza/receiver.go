@@ -0,0 +1,241 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+// logsRoute pairs one of fanoutConverter's Converters with the
+// consumer.Logs it feeds and, when BaseConfig.RetryOnFailure is set, the
+// retryQueue retrying whatever that consumer rejects retryably.
+type logsRoute struct {
+	converter *Converter
+	consumer  consumer.Logs
+	retry     *retryQueue
+}
+
+// receiver is what actually drives the Converter(s) and ProfilesConverter
+// built from a BaseConfig: it flushes them on their FlushInterval, hands
+// every batch to its consumer, retries or dead-letters what that consumer
+// rejects, and checkpoints through persister only as far as the consumer
+// actually accepted. Without it, fanoutConverter, ProfilesConverter,
+// retryQueue, deadLetterSink, and persister are each exercised only by
+// their own unit tests and never by anything a real receiver runs.
+type receiver struct {
+	logger *zap.SugaredLogger
+
+	persister *persister
+
+	logsRoutes []*logsRoute
+
+	profiles         *ProfilesConverter
+	profilesConsumer ProfilesConsumer
+
+	deadLetter *deadLetterSink
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+// newReceiver builds a receiver for cfg over client.
+//
+// logsConsumers is ignored unless cfg has SignalLogs, in which case it
+// must have one entry per cfg.Consumers, or exactly one entry when
+// Consumers is empty (the single-pipeline case every stanza-based
+// receiver historically supported). profilesConsumer is required when cfg
+// has SignalProfiles, and ignored otherwise.
+func newReceiver(logger *zap.SugaredLogger, cfg BaseConfig, client storage.Client, logsConsumers []consumer.Logs, profilesConsumer ProfilesConsumer) (*receiver, error) {
+	p, err := newPersister(client)
+	if err != nil {
+		return nil, fmt.Errorf("build persister: %w", err)
+	}
+
+	r := &receiver{
+		logger:    logger,
+		persister: p,
+		stopCh:    make(chan struct{}),
+	}
+
+	if cfg.DeadLetterStorage != "" {
+		r.deadLetter = &deadLetterSink{persister: p, key: cfg.DeadLetterStorage}
+	}
+
+	if cfg.HasSignal(SignalLogs) {
+		wantConsumers := len(cfg.Consumers)
+		if wantConsumers == 0 {
+			wantConsumers = 1
+		}
+		if len(logsConsumers) != wantConsumers {
+			return nil, fmt.Errorf("%d logs consumer(s) configured but %d supplied", wantConsumers, len(logsConsumers))
+		}
+
+		fanout := newFanoutConverter(cfg.Converter, cfg.Consumers)
+		for i, converter := range fanout.converters {
+			route := &logsRoute{converter: converter, consumer: logsConsumers[i]}
+			if cfg.RetryOnFailure {
+				route.retry = newRetryQueue(logsConsumers[i], cfg.MaxElapsedTime, r.onPermanentLogsFailure)
+			}
+			r.logsRoutes = append(r.logsRoutes, route)
+		}
+	}
+
+	if cfg.HasSignal(SignalProfiles) {
+		if profilesConsumer == nil {
+			return nil, fmt.Errorf("receiver configured for signal %q but no profiles consumer was supplied", SignalProfiles)
+		}
+		r.profiles = NewProfilesConverter(cfg.Profiles)
+		r.profilesConsumer = profilesConsumer
+	}
+
+	return r, nil
+}
+
+// Start launches the flush-timer and consume loop for every configured
+// logs route, plus the profiles equivalent if configured. It returns
+// immediately; failures inside those loops are logged rather than
+// returned, since a rejected batch is expected to be retried or
+// dead-lettered on its own, not to abort the receiver.
+func (r *receiver) Start(context.Context) error {
+	for _, route := range r.logsRoutes {
+		route := route
+		r.wg.Add(2)
+		go func() { defer r.wg.Done(); r.runFlushTimer(route.converter.flushInterval, route.converter.Flush) }()
+		go func() { defer r.wg.Done(); r.consumeLogs(route) }()
+	}
+
+	if r.profiles != nil {
+		r.wg.Add(2)
+		go func() { defer r.wg.Done(); r.runFlushTimer(r.profiles.flushInterval, r.profiles.Flush) }()
+		go func() { defer r.wg.Done(); r.consumeProfiles() }()
+	}
+
+	return nil
+}
+
+// Shutdown stops every flush-timer and consume loop, tears down the
+// underlying Converters and retryQueues, and waits for those loops to
+// return. It does not flush pending entries first, and batches still
+// queued in a Converter's output channel may go undelivered.
+func (r *receiver) Shutdown(context.Context) error {
+	close(r.stopCh)
+	for _, route := range r.logsRoutes {
+		if route.retry != nil {
+			route.retry.Stop()
+		}
+		route.converter.Stop()
+	}
+	if r.profiles != nil {
+		r.profiles.Stop()
+	}
+	r.wg.Wait()
+	return nil
+}
+
+func (r *receiver) runFlushTimer(interval time.Duration, flush func(context.Context) error) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := flush(context.Background()); err != nil {
+				r.logf("flush on interval: %v", err)
+			}
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+func (r *receiver) consumeLogs(route *logsRoute) {
+	for {
+		select {
+		case batch, ok := <-route.converter.OutChannel():
+			if !ok {
+				return
+			}
+			r.deliverLogs(route, batch)
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// deliverLogs hands batch.Logs to route.consumer, advances persisted
+// checkpoints as far as the consumer actually accepted, and routes a
+// rejected batch to retry (if retryable and configured) or to dead letter.
+func (r *receiver) deliverLogs(route *logsRoute, batch convertedLogs) {
+	ctx := context.Background()
+	started := time.Now()
+	consumeErr := route.consumer.ConsumeLogs(ctx, batch.Logs)
+
+	if err := CheckpointAccepted(ctx, r.persister, batch.Checkpoints, consumeErr); err != nil {
+		r.logf("advance checkpoints after consume: %v", err)
+	}
+
+	if consumeErr == nil {
+		return
+	}
+
+	if ce := AsConsumerError(consumeErr); ce.Retryable && route.retry != nil {
+		route.retry.Submit(batch.Logs, started)
+		return
+	}
+	r.onPermanentLogsFailure(ctx, batch.Logs, consumeErr)
+}
+
+// onPermanentLogsFailure is the retryQueue.onFailure callback, and is also
+// called directly for a batch that failed non-retryably on its first
+// attempt. r.deadLetter is nil-safe: with no dead letter sink configured,
+// Send just reports the drop.
+func (r *receiver) onPermanentLogsFailure(ctx context.Context, logs plog.Logs, cause error) {
+	if err := r.deadLetter.Send(ctx, logs, cause); err != nil {
+		r.logf("dead letter: %v", err)
+	}
+}
+
+func (r *receiver) consumeProfiles() {
+	for {
+		select {
+		case profiles, ok := <-r.profiles.OutChannel():
+			if !ok {
+				return
+			}
+			if err := r.profilesConsumer.ConsumeProfiles(context.Background(), profiles); err != nil {
+				r.logf("consume profiles: %v", err)
+			}
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+func (r *receiver) logf(format string, args ...interface{}) {
+	if r.logger == nil {
+		return
+	}
+	r.logger.Warnf(format, args...)
+}
@@ -0,0 +1,187 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-log-collection/entry"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func baseLogsCfg() BaseConfig {
+	return BaseConfig{Converter: ConverterConfig{MaxFlushCount: 1}}
+}
+
+func TestReceiverDeliversFlushedLogsToConsumer(t *testing.T) {
+	sink := &consumertest.LogsSink{}
+	r, err := newReceiver(nil, baseLogsCfg(), newMockClient(), []consumer.Logs{sink}, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background()))
+	defer func() { require.NoError(t, r.Shutdown(context.Background())) }()
+
+	require.NoError(t, r.logsRoutes[0].converter.Batch([]*entry.Entry{{Timestamp: time.Now()}}))
+
+	require.Eventually(t, func() bool {
+		return len(sink.AllLogs()) == 1
+	}, time.Second, time.Millisecond)
+}
+
+// TestReceiverCheckpointsOnlyWhatConsumerAccepted verifies the receiver
+// calls CheckpointAccepted with each flushed entry's checkpoint after a
+// real ConsumeLogs call, instead of never invoking it at all.
+func TestReceiverCheckpointsOnlyWhatConsumerAccepted(t *testing.T) {
+	sink := &consumertest.LogsSink{}
+	r, err := newReceiver(nil, baseLogsCfg(), newMockClient(), []consumer.Logs{sink}, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background()))
+	defer func() { require.NoError(t, r.Shutdown(context.Background())) }()
+
+	e := &entry.Entry{
+		Timestamp: time.Now(),
+		Attributes: map[string]interface{}{
+			checkpointKeyAttribute:   "file-a",
+			checkpointValueAttribute: "10",
+		},
+	}
+	require.NoError(t, r.logsRoutes[0].converter.Batch([]*entry.Entry{e}))
+
+	require.Eventually(t, func() bool {
+		v, gerr := r.persister.Get(context.Background(), "file-a")
+		return gerr == nil && string(v) == "10"
+	}, time.Second, time.Millisecond)
+}
+
+// TestReceiverDeadLettersPermanentFailures verifies a non-retryable
+// ConsumeLogs failure lands in the configured dead letter sink instead of
+// being silently dropped.
+func TestReceiverDeadLettersPermanentFailures(t *testing.T) {
+	cfg := baseLogsCfg()
+	cfg.DeadLetterStorage = "dead-letter"
+	rejecter := &mockLogsRejecter{}
+	r, err := newReceiver(nil, cfg, newMockClient(), []consumer.Logs{rejecter}, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background()))
+	defer func() { require.NoError(t, r.Shutdown(context.Background())) }()
+
+	require.NoError(t, r.logsRoutes[0].converter.Batch([]*entry.Entry{{Timestamp: time.Now()}}))
+
+	require.Eventually(t, func() bool {
+		manifest, merr := r.deadLetter.readManifest(context.Background())
+		return merr == nil && len(manifest.Keys) == 1
+	}, time.Second, time.Millisecond)
+}
+
+// flakyLogsConsumer fails the first failCount calls with a retryable
+// ConsumerError, then succeeds, mirroring flakyConsumer in
+// retry_queue_test.go at the receiver level.
+type flakyLogsConsumer struct {
+	consumertest.LogsSink
+	failCount int32
+}
+
+func (f *flakyLogsConsumer) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	if atomic.AddInt32(&f.failCount, -1) >= 0 {
+		return &ConsumerError{Retryable: true}
+	}
+	return f.LogsSink.ConsumeLogs(ctx, ld)
+}
+
+// TestReceiverRetriesRetryableFailures verifies a retryable ConsumeLogs
+// rejection is handed to a retryQueue rather than dead-lettered
+// immediately, and eventually succeeds.
+func TestReceiverRetriesRetryableFailures(t *testing.T) {
+	cfg := baseLogsCfg()
+	cfg.RetryOnFailure = true
+	consumer := &flakyLogsConsumer{failCount: 1}
+	r, err := newReceiver(nil, cfg, newMockClient(), []consumer.Logs{consumer}, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background()))
+	defer func() { require.NoError(t, r.Shutdown(context.Background())) }()
+
+	require.NoError(t, r.logsRoutes[0].converter.Batch([]*entry.Entry{{Timestamp: time.Now()}}))
+
+	require.Eventually(t, func() bool {
+		return len(consumer.AllLogs()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReceiverRequiresProfilesConsumerWhenSignalConfigured(t *testing.T) {
+	cfg := BaseConfig{Signals: []Signal{SignalProfiles}, Profiles: ConverterConfig{MaxFlushCount: 1}}
+	_, err := newReceiver(nil, cfg, newMockClient(), nil, nil)
+	require.Error(t, err)
+}
+
+// mockProfilesConsumer's received field is written from the receiver's
+// consumeProfiles goroutine and read from the test's require.Eventually
+// polling closure, so both sides need mu - without it, the test data race
+// is real, not just theoretical, since Start launches consumeProfiles
+// concurrently with the test goroutine.
+type mockProfilesConsumer struct {
+	mu       sync.Mutex
+	received []pprofile.Profiles
+}
+
+func (m *mockProfilesConsumer) ConsumeProfiles(_ context.Context, p pprofile.Profiles) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received = append(m.received, p)
+	return nil
+}
+
+func (m *mockProfilesConsumer) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.received)
+}
+
+func TestReceiverDeliversFlushedProfilesToConsumer(t *testing.T) {
+	cfg := BaseConfig{Signals: []Signal{SignalProfiles}, Profiles: ConverterConfig{MaxFlushCount: 1}}
+	profilesConsumer := &mockProfilesConsumer{}
+	r, err := newReceiver(nil, cfg, newMockClient(), nil, profilesConsumer)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background()))
+	defer func() { require.NoError(t, r.Shutdown(context.Background())) }()
+
+	require.NoError(t, r.profiles.Batch([]*entry.Entry{{
+		Timestamp: time.Now(),
+		Body: map[string]interface{}{
+			"sample_type": []map[string]interface{}{},
+			"samples":     []map[string]interface{}{},
+		},
+	}}))
+
+	require.Eventually(t, func() bool {
+		return profilesConsumer.len() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestReceiverRejectsMismatchedConsumerCount(t *testing.T) {
+	cfg := baseLogsCfg()
+	cfg.Consumers = []ConsumerConfig{
+		{Converter: ConverterConfig{MaxFlushCount: 1}},
+		{Converter: ConverterConfig{MaxFlushCount: 1}},
+	}
+	_, err := newReceiver(nil, cfg, newMockClient(), []consumer.Logs{&consumertest.LogsSink{}}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,143 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	retryInitialInterval = 5 * time.Second
+	retryMaxInterval     = 30 * time.Second
+	retryMultiplier      = 1.5
+	retryQueueCapacity   = 64
+)
+
+// retryItem is one plog.Logs batch waiting to be retried against
+// consumer, tracked since its first delivery attempt so maxElapsedTime
+// can be enforced across every subsequent retry.
+type retryItem struct {
+	logs    plog.Logs
+	attempt int
+	started time.Time
+}
+
+// onPermanentFailure is called once for a batch that either failed with a
+// non-retryable ConsumerError, or that exhausted its retry budget.
+type onPermanentFailure func(ctx context.Context, logs plog.Logs, cause error)
+
+// retryQueue retries plog.Logs batches that consumer rejected with a
+// retryable ConsumerError (see ConsumerError.Retryable), backing off
+// exponentially with jitter between attempts and giving up once
+// maxElapsedTime has passed since the batch's first attempt. Batches that
+// give up, or that a retry attempt rejects non-retryably, are handed to
+// onFailure for dead-lettering.
+type retryQueue struct {
+	consumer       consumer.Logs
+	maxElapsedTime time.Duration
+	onFailure      onPermanentFailure
+
+	items chan retryItem
+	done  chan struct{}
+}
+
+func newRetryQueue(c consumer.Logs, maxElapsedTime time.Duration, onFailure onPermanentFailure) *retryQueue {
+	q := &retryQueue{
+		consumer:       c,
+		maxElapsedTime: maxElapsedTime,
+		onFailure:      onFailure,
+		items:          make(chan retryItem, retryQueueCapacity),
+		done:           make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Submit enqueues logs for retry, recording started as its first attempt
+// time so maxElapsedTime is measured from there rather than from whenever
+// it happens to reach the front of the queue.
+func (q *retryQueue) Submit(logs plog.Logs, started time.Time) {
+	q.enqueue(retryItem{logs: logs, started: started})
+}
+
+func (q *retryQueue) enqueue(item retryItem) {
+	select {
+	case q.items <- item:
+	case <-q.done:
+	}
+}
+
+func (q *retryQueue) run() {
+	for {
+		select {
+		case item := <-q.items:
+			q.retry(item)
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (q *retryQueue) retry(item retryItem) {
+	ctx := context.Background()
+	if q.maxElapsedTime > 0 && time.Since(item.started) > q.maxElapsedTime {
+		q.onFailure(ctx, item.logs, fmt.Errorf("retry budget exhausted after %d attempts: %w", item.attempt, errRetryBudgetExhausted))
+		return
+	}
+
+	select {
+	case <-time.After(retryBackoff(item.attempt)):
+	case <-q.done:
+		return
+	}
+
+	err := q.consumer.ConsumeLogs(ctx, item.logs)
+	if err == nil {
+		return
+	}
+
+	ce := AsConsumerError(err)
+	if !ce.Retryable {
+		q.onFailure(ctx, item.logs, ce)
+		return
+	}
+
+	item.attempt++
+	q.enqueue(item)
+}
+
+// Stop stops retrying and drops any items still queued.
+func (q *retryQueue) Stop() {
+	close(q.done)
+}
+
+var errRetryBudgetExhausted = fmt.Errorf("retry budget exhausted")
+
+// retryBackoff returns the exponential-backoff-with-full-jitter delay for
+// the given retry attempt (0-indexed), capped at retryMaxInterval.
+func retryBackoff(attempt int) time.Duration {
+	interval := float64(retryInitialInterval) * math.Pow(retryMultiplier, float64(attempt))
+	if interval > float64(retryMaxInterval) {
+		interval = float64(retryMaxInterval)
+	}
+	return time.Duration(rand.Float64() * interval) //nolint:gosec // jitter, not a security boundary
+}
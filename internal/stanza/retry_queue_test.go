@@ -0,0 +1,111 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stanza
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// flakyConsumer fails the first failCount calls with a retryable
+// ConsumerError, then succeeds.
+type flakyConsumer struct {
+	consumertest.LogsSink
+	failCount int32
+}
+
+func (f *flakyConsumer) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	if atomic.AddInt32(&f.failCount, -1) >= 0 {
+		return &ConsumerError{Retryable: true, Cause: errors.New("backpressured")}
+	}
+	return f.LogsSink.ConsumeLogs(ctx, ld)
+}
+
+func TestRetryQueueRetriesUntilSuccess(t *testing.T) {
+	c := &flakyConsumer{failCount: 2}
+	failures := make(chan error, 1)
+	q := newRetryQueue(c, time.Minute, func(_ context.Context, _ plog.Logs, cause error) {
+		failures <- cause
+	})
+	defer q.Stop()
+
+	q.Submit(plog.NewLogs(), time.Now())
+
+	// Three attempts wait at most 5s + 7.5s + 11.25s of jittered backoff.
+	require.Eventually(t, func() bool {
+		return len(c.AllLogs()) == 1
+	}, 30*time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-failures:
+		t.Fatalf("unexpected permanent failure: %v", err)
+	default:
+	}
+}
+
+func TestRetryQueueDeadLettersNonRetryableError(t *testing.T) {
+	boom := errors.New("boom")
+	rejecting := &mockLogsRejecterWithCause{cause: boom}
+	failures := make(chan error, 1)
+	q := newRetryQueue(rejecting, time.Minute, func(_ context.Context, _ plog.Logs, cause error) {
+		failures <- cause
+	})
+	defer q.Stop()
+
+	q.Submit(plog.NewLogs(), time.Now())
+
+	select {
+	case err := <-failures:
+		require.ErrorIs(t, err, boom)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a permanent failure callback")
+	}
+}
+
+func TestRetryQueueGivesUpAfterMaxElapsedTime(t *testing.T) {
+	c := &flakyConsumer{failCount: 1 << 20}
+	failures := make(chan error, 1)
+	q := newRetryQueue(c, time.Nanosecond, func(_ context.Context, _ plog.Logs, cause error) {
+		failures <- cause
+	})
+	defer q.Stop()
+
+	q.Submit(plog.NewLogs(), time.Now().Add(-time.Hour))
+
+	select {
+	case err := <-failures:
+		require.ErrorIs(t, err, errRetryBudgetExhausted)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the retry budget to be exhausted")
+	}
+}
+
+// mockLogsRejecterWithCause always rejects with a non-retryable
+// ConsumerError wrapping cause.
+type mockLogsRejecterWithCause struct {
+	consumertest.LogsSink
+	cause error
+}
+
+func (m *mockLogsRejecterWithCause) ConsumeLogs(_ context.Context, _ plog.Logs) error {
+	return &ConsumerError{Retryable: false, Cause: m.cause}
+}